@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/davidalecrim/extreme/config"
+	"github.com/davidalecrim/extreme/observability"
 	"github.com/davidalecrim/extreme/proxy"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
@@ -27,14 +31,41 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, logOpts))
 	slog.SetDefault(logger)
 
-	p, err := proxy.New(cfg, logger)
+	var metrics *observability.Metrics
+	var registry *prometheus.Registry
+
+	if cfg.Admin.Address != "" {
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+		metrics = observability.NewMetrics(registry)
+	}
+
+	p, err := proxy.New(cfg, logger, metrics)
 	if err != nil {
 		logger.Error("failed to create proxy", "error", err)
 		os.Exit(1)
 	}
 
+	var admin *observability.Server
+
+	if cfg.Admin.Address != "" {
+		admin = observability.NewServer(observability.ServerConfig{
+			Address:      cfg.Admin.Address,
+			ReadTimeout:  cfg.Admin.ReadTimeout,
+			WriteTimeout: cfg.Admin.WriteTimeout,
+		}, registry, logger, func(mux *http.ServeMux) {
+			p.RegisterAdminRoutes(mux, *configPath)
+		})
+
+		go func() {
+			if err := admin.Start(); err != nil {
+				logger.Error("failed to start admin server", "error", err)
+			}
+		}()
+	}
+
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
 		if err := p.Start(); err != nil {
@@ -43,9 +74,35 @@ func main() {
 		}
 	}()
 
-	<-sigChan
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+
+		logger.Info("received SIGHUP, reloading configuration", "path", *configPath)
+
+		newCfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			logger.Error("failed to reload configuration", "error", err)
+			continue
+		}
+
+		diff := p.Reload(newCfg)
+		logger.Info("configuration reloaded",
+			"routes_changed", diff.RoutesChanged,
+			"routes_added", diff.RoutesAdded,
+			"listener_rebound", diff.ListenerRebound,
+			"errors", diff.Errors,
+		)
+	}
 
 	if err := p.Shutdown(); err != nil {
 		logger.Error("error during shutdown", "error", err)
 	}
+
+	if admin != nil {
+		if err := admin.Shutdown(context.Background()); err != nil {
+			logger.Error("error during admin server shutdown", "error", err)
+		}
+	}
 }
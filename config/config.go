@@ -5,6 +5,7 @@ package config
 
 import (
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -12,37 +13,140 @@ import (
 
 // Config represents the complete proxy server configuration.
 // It contains all settings necessary for running the proxy server,
-// including server parameters, keep-alive settings, backend configurations,
+// including server parameters, keep-alive settings, routing rules,
 // connection pooling options, and logging preferences.
 type Config struct {
-	Server         ServerConfig  `mapstructure:"server"`
-	BackendSockets []string      `mapstructure:"backends"`
-	Logging        LoggingConfig `mapstructure:"logging"`
-	PreWarm        PreWarmConfig `mapstructure:"pre_warm"`
+	Server      ServerConfig      `mapstructure:"server" json:"server"`
+	Routes      RoutesConfig      `mapstructure:"routes" json:"routes"`
+	Logging     LoggingConfig     `mapstructure:"logging" json:"logging"`
+	PreWarm     PreWarmConfig     `mapstructure:"pre_warm" json:"pre_warm"`
+	Admin       AdminConfig       `mapstructure:"admin" json:"admin"`
+	HealthCheck HealthCheckConfig `mapstructure:"health_check" json:"health_check"`
 }
 
 // ServerConfig defines the core server settings including address binding,
 // timeouts, and connection limits.
 type ServerConfig struct {
-	ListenAddress    string        `mapstructure:"listen_address"`
-	KeepAliveTimeout time.Duration `mapstructure:"keep_alive_timeout"`
-	ReadTimeout      time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
+	ListenAddress    string        `mapstructure:"listen_address" json:"listen_address"`
+	KeepAliveTimeout time.Duration `mapstructure:"keep_alive_timeout" json:"keep_alive_timeout"`
+	ReadTimeout      time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
+	WriteTimeout     time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
+	NotFoundBody     string        `mapstructure:"not_found_body" json:"not_found_body"`
+}
+
+// RoutesConfig maps a Host header value to the route that should serve it.
+// The special key "*" defines the fallback route used when no other host
+// matches the incoming request.
+type RoutesConfig map[string]RouteConfig
+
+// FallbackHost is the routes key used as the catch-all route for hosts
+// that don't match any other entry in RoutesConfig.
+const FallbackHost = "*"
+
+// RouteConfig describes the ordered list of backends that serve a single
+// host, the load-balancing strategy used to pick among them, and the retry
+// policy applied while forwarding to them.
+type RouteConfig struct {
+	Backends []BackendConfig `mapstructure:"backends" json:"backends"`
+
+	// Strategy selects the Balancer used for this route: "round_robin"
+	// (default), "least_conn", "ewma_latency", or "consistent_hash".
+	Strategy string `mapstructure:"strategy" json:"strategy"`
+
+	// HashKey configures what the "consistent_hash" strategy hashes to
+	// pick a backend. "path" (the default) hashes the request path;
+	// any other value is treated as a request header name.
+	HashKey string `mapstructure:"hash_key" json:"hash_key"`
+}
+
+// BackendConfig describes a single backend within a route and the retry
+// policy to apply when it fails. Retries is the number of additional
+// backends to try after this one returns a network error or a 5xx
+// response, Delay is how long to wait before trying the next backend,
+// and Timeout, if set, overrides the client's default request timeout
+// for requests sent to this backend.
+type BackendConfig struct {
+	Address string        `mapstructure:"address" json:"address"`
+	Retries int           `mapstructure:"retries" json:"retries"`
+	Delay   time.Duration `mapstructure:"delay" json:"delay"`
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+
+	// TLS configures the client used when Address has the "https://"
+	// scheme. It's ignored for "unix://" and "tcp://" backends.
+	TLS BackendTLSConfig `mapstructure:"tls" json:"tls"`
+}
+
+// BackendTLSConfig configures the TLS client used to reach an "https://"
+// backend: the trusted CA bundle, SNI override, and an optional mTLS
+// client certificate.
+type BackendTLSConfig struct {
+	CAFile             string `mapstructure:"ca_file" json:"ca_file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" json:"insecure_skip_verify"`
+	ServerName         string `mapstructure:"server_name" json:"server_name"`
+	ClientCertFile     string `mapstructure:"client_cert_file" json:"client_cert_file"`
+	ClientKeyFile      string `mapstructure:"client_key_file" json:"client_key_file"`
+}
+
+// Backend address schemes accepted in BackendConfig.Address.
+const (
+	SchemeUnix  = "unix"
+	SchemeTCP   = "tcp"
+	SchemeHTTPS = "https"
+)
+
+// ParseBackendAddress splits a configured backend address into its scheme
+// and dial target. A bare address with no "scheme://" prefix is treated as
+// "unix://<address>", preserving the proxy's original unix-socket-only
+// behavior.
+func ParseBackendAddress(address string) (scheme, target string) {
+	if idx := strings.Index(address, "://"); idx != -1 {
+		return address[:idx], address[idx+3:]
+	}
+	return SchemeUnix, address
+}
+
+// HealthCheckConfig controls the active health checker that probes every
+// configured backend and ejects it from balancing once it crosses
+// UnhealthyThreshold consecutive failures, reinstating it after
+// HealthyThreshold consecutive successes.
+type HealthCheckConfig struct {
+	Enabled            bool          `mapstructure:"enabled" json:"enabled"`
+	Interval           time.Duration `mapstructure:"interval" json:"interval"`
+	Path               string        `mapstructure:"path" json:"path"`
+	Method             string        `mapstructure:"method" json:"method"`
+	Timeout            time.Duration `mapstructure:"timeout" json:"timeout"`
+	UnhealthyThreshold int           `mapstructure:"unhealthy_threshold" json:"unhealthy_threshold"`
+	HealthyThreshold   int           `mapstructure:"healthy_threshold" json:"healthy_threshold"`
+	ExpectedStatus     int           `mapstructure:"expected_status" json:"expected_status"`
+}
+
+// AdminConfig defines the admin HTTP listener that serves Prometheus
+// metrics, pprof profiling endpoints, and the runtime admin API,
+// independently of the proxy's main listener. Admin is disabled when
+// Address is empty.
+type AdminConfig struct {
+	Address      string        `mapstructure:"address" json:"address"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
+
+	// Token guards every admin API endpoint under /api/admin/, reads and
+	// writes alike. Requests must carry it as "Authorization: Bearer <token>".
+	Token string `mapstructure:"token" json:"-"`
 }
 
 // PreWarmConfig defines the settings for pre-warming connections to backends.
 // It includes whether pre-warming is enabled and the number of requests to send
 // to each backend during pre-warming.
 type PreWarmConfig struct {
-	Enabled            bool `mapstructure:"enabled"`
-	RequestsPerBackend int  `mapstructure:"requests_per_backend"`
+	Enabled            bool `mapstructure:"enabled" json:"enabled"`
+	RequestsPerBackend int  `mapstructure:"requests_per_backend" json:"requests_per_backend"`
 }
 
 // LoggingConfig contains settings for controlling the proxy's logging behavior,
 // including enabling/disabling logging and setting the log level.
 type LoggingConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Level   string `mapstructure:"level"`
+	Enabled bool   `mapstructure:"enabled" json:"enabled"`
+	Level   string `mapstructure:"level" json:"level"`
 }
 
 // GetLevel converts the string log level from the configuration
@@ -0,0 +1,162 @@
+// Package observability provides the proxy's Prometheus metrics and the
+// admin HTTP listener that exposes them alongside pprof profiling
+// endpoints, independent of the main proxy listener.
+package observability
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors instrumenting request handling
+// and backend connection pools. A Metrics value is safe for concurrent use.
+type Metrics struct {
+	RequestDuration *prometheus.HistogramVec
+	RequestsTotal   *prometheus.CounterVec
+	UpstreamErrors  *prometheus.CounterVec
+	InFlight        *prometheus.GaugeVec
+	PreWarmTotal    *prometheus.CounterVec
+	PoolOpenConns   *prometheus.GaugeVec
+	BackendEjected  *prometheus.GaugeVec
+}
+
+// NewMetrics builds the proxy's collectors and registers them with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "proxy_request_duration_seconds",
+			Help:    "Latency of proxied requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"backend", "method", "status_class"}),
+
+		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "proxy_requests_total",
+			Help: "Total number of proxied requests.",
+		}, []string{"backend", "method", "status_class"}),
+
+		UpstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "proxy_upstream_errors_total",
+			Help: "Total number of requests that failed to reach or were rejected by a backend.",
+		}, []string{"backend"}),
+
+		InFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "proxy_inflight_requests",
+			Help: "Number of requests currently in flight per backend.",
+		}, []string{"backend"}),
+
+		PreWarmTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "proxy_prewarm_total",
+			Help: "Total number of connection pre-warm attempts, labeled by outcome.",
+		}, []string{"backend", "outcome"}),
+
+		PoolOpenConns: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "proxy_backend_open_connections",
+			Help: "Open connections in each backend's fasthttp.HostClient pool.",
+		}, []string{"backend"}),
+
+		BackendEjected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "proxy_backend_ejected",
+			Help: "Whether a backend is currently ejected from balancing by the health checker (1) or not (0).",
+		}, []string{"backend"}),
+	}
+
+	reg.MustRegister(
+		m.RequestDuration,
+		m.RequestsTotal,
+		m.UpstreamErrors,
+		m.InFlight,
+		m.PreWarmTotal,
+		m.PoolOpenConns,
+		m.BackendEjected,
+	)
+
+	return m
+}
+
+// StatusClass buckets an HTTP status code into its "2xx"/"4xx"/"5xx" class
+// for use as a low-cardinality metric label.
+func StatusClass(statusCode int) string {
+	switch statusCode / 100 {
+	case 1:
+		return "1xx"
+	case 2:
+		return "2xx"
+	case 3:
+		return "3xx"
+	case 4:
+		return "4xx"
+	case 5:
+		return "5xx"
+	default:
+		return "unknown"
+	}
+}
+
+// Server is the admin HTTP listener exposing Prometheus metrics at
+// /metrics and pprof profiling endpoints under /debug/pprof/. It runs
+// independently of the proxy's main fasthttp listener.
+type Server struct {
+	httpServer *http.Server
+	logger     *slog.Logger
+}
+
+// NewServer builds the admin server. registry is exposed verbatim at
+// /metrics, so callers that need the default Go/process collectors should
+// register them on it beforehand. Each mount function is given the chance
+// to register additional routes on the same mux, letting callers outside
+// this package (e.g. the proxy's runtime admin API) share the listener.
+func NewServer(cfg ServerConfig, registry *prometheus.Registry, logger *slog.Logger, mount ...func(*http.ServeMux)) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	for _, m := range mount {
+		m(mux)
+	}
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:         cfg.Address,
+			Handler:      mux,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+		},
+		logger: logger,
+	}
+}
+
+// ServerConfig carries the admin listener settings out of config.AdminConfig
+// so this package doesn't need to import config for a handful of fields.
+type ServerConfig struct {
+	Address      string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// Start begins serving the admin listener. It blocks until Shutdown is
+// called or the listener encounters an error.
+func (s *Server) Start() error {
+	s.logger.Info("starting admin server", "address", s.httpServer.Addr)
+
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown gracefully stops the admin listener.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("shutting down admin server")
+	return s.httpServer.Shutdown(ctx)
+}
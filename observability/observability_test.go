@@ -0,0 +1,107 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStatusClass(t *testing.T) {
+	cases := map[int]string{
+		100: "1xx",
+		200: "2xx",
+		204: "2xx",
+		301: "3xx",
+		404: "4xx",
+		500: "5xx",
+		599: "5xx",
+		999: "unknown",
+	}
+
+	for status, want := range cases {
+		if got := StatusClass(status); got != want {
+			t.Errorf("StatusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestNewMetricsRegistersAllCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg)
+
+	m.RequestDuration.WithLabelValues("backend", "GET", "2xx").Observe(0.01)
+	m.RequestsTotal.WithLabelValues("backend", "GET", "2xx").Inc()
+	m.UpstreamErrors.WithLabelValues("backend").Inc()
+	m.InFlight.WithLabelValues("host").Set(1)
+	m.PreWarmTotal.WithLabelValues("backend", "success").Inc()
+	m.PoolOpenConns.WithLabelValues("backend").Set(2)
+	m.BackendEjected.WithLabelValues("backend").Set(1)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+
+	names := make(map[string]bool, len(families))
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+
+	for _, name := range []string{
+		"proxy_request_duration_seconds",
+		"proxy_requests_total",
+		"proxy_upstream_errors_total",
+		"proxy_inflight_requests",
+		"proxy_prewarm_total",
+		"proxy_backend_open_connections",
+		"proxy_backend_ejected",
+	} {
+		if !names[name] {
+			t.Errorf("expected collector %q to be registered and gathered", name)
+		}
+	}
+}
+
+func TestServerServesMetricsAndMountedRoutes(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	NewMetrics(reg)
+
+	mounted := false
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	s := NewServer(ServerConfig{Address: "127.0.0.1:0"}, reg, logger, func(mux *http.ServeMux) {
+		mounted = true
+		mux.HandleFunc("/api/admin/ping", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	if !mounted {
+		t.Fatal("expected the mount function passed to NewServer to be called")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want 200", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/ping", nil)
+	rec = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/admin/ping = %d, want 200", rec.Code)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed on a server that was never started: %v", err)
+	}
+}
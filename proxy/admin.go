@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/davidalecrim/extreme/config"
+)
+
+// RegisterAdminRoutes mounts the proxy's runtime admin API onto mux:
+// inspecting the effective config, replacing a route's backends, draining
+// a single backend, and reloading config from configPath. Every endpoint,
+// including GET /api/admin/config, requires a bearer token when
+// config.AdminConfig.Token is set.
+func (p *Proxy) RegisterAdminRoutes(mux *http.ServeMux, configPath string) {
+	mux.HandleFunc("GET /api/admin/config", p.requireToken(p.handleGetConfig))
+	mux.HandleFunc("PUT /api/admin/backends", p.requireToken(p.handlePutBackends))
+	mux.HandleFunc("POST /api/admin/backends/{addr}/drain", p.requireToken(p.handleDrainBackend))
+	mux.HandleFunc("POST /api/admin/reload", p.requireToken(func(w http.ResponseWriter, r *http.Request) {
+		p.handleReload(w, r, configPath)
+	}))
+}
+
+// requireToken wraps next with a bearer-token check against
+// config.AdminConfig.Token. The check is skipped entirely when no token is
+// configured.
+func (p *Proxy) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := p.config.Admin.Token
+		if token == "" {
+			next(w, r)
+			return
+		}
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || got != token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (p *Proxy) handleGetConfig(w http.ResponseWriter, _ *http.Request) {
+	p.adminMu.Lock()
+	cfg := *p.config
+	// Routes is a map, so copying the Config struct above still leaves cfg
+	// sharing it with p.config. Copy it too, under the same lock, so the
+	// Encode below doesn't race with a concurrent PUT/reload writing to
+	// p.config.Routes.
+	cfg.Routes = make(config.RoutesConfig, len(p.config.Routes))
+	for host, routeCfg := range p.config.Routes {
+		cfg.Routes[host] = routeCfg
+	}
+	p.adminMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cfg); err != nil {
+		p.logger.Error("failed to encode admin config response", "error", err)
+	}
+}
+
+// backendsRequest is the body for PUT /api/admin/backends. Strategy and
+// HashKey are optional; when empty, the route's current values are kept.
+type backendsRequest struct {
+	Host     string                 `json:"host"`
+	Backends []config.BackendConfig `json:"backends"`
+	Strategy string                 `json:"strategy"`
+	HashKey  string                 `json:"hash_key"`
+}
+
+func (p *Proxy) handlePutBackends(w http.ResponseWriter, r *http.Request) {
+	var req backendsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rt := p.routeByHost(req.Host)
+	if rt == nil {
+		http.Error(w, "unknown host: "+req.Host, http.StatusNotFound)
+		return
+	}
+
+	p.adminMu.Lock()
+	defer p.adminMu.Unlock()
+
+	old := rt.snapshot()
+
+	routeCfg := config.RouteConfig{
+		Backends: req.Backends,
+		Strategy: firstNonEmpty(req.Strategy, old.strategy),
+		HashKey:  firstNonEmpty(req.HashKey, old.hashKey),
+	}
+
+	newState, err := buildRouteState(routeCfg, p.config.Server, p.config.PreWarm, p.logger, p.metrics)
+	if err != nil {
+		http.Error(w, "invalid backends: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rt.state.Store(newState)
+	closeRemovedBackends(old, newState)
+
+	p.config.Routes[req.Host] = routeCfg
+
+	p.logger.Info("admin API replaced route backends",
+		"host", req.Host,
+		"backends", len(routeCfg.Backends),
+	)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *Proxy) handleDrainBackend(w http.ResponseWriter, r *http.Request) {
+	addr := r.PathValue("addr")
+
+	for _, rt := range p.allRoutes() {
+		if target, ok := rt.snapshot().byAddress[addr]; ok {
+			target.draining.Store(true)
+			p.logger.Info("backend marked draining", "backend", addr)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	http.Error(w, "unknown backend: "+addr, http.StatusNotFound)
+}
+
+func (p *Proxy) handleReload(w http.ResponseWriter, _ *http.Request, configPath string) {
+	newCfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		http.Error(w, "failed to reload config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	diff := p.Reload(newCfg)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		p.logger.Error("failed to encode reload response", "error", err)
+	}
+}
+
+// ReloadDiff summarizes what Reload actually applied from a reloaded
+// config, since not every field can be changed on a running Proxy.
+type ReloadDiff struct {
+	RoutesChanged []string `json:"routes_changed"`
+	RoutesAdded   []string `json:"routes_added"`
+
+	// ListenerRebound is true when ListenAddress changed and Reload opened
+	// a new listener and fasthttp.Server for it, draining the previous one
+	// in the background.
+	ListenerRebound bool `json:"listener_rebound"`
+
+	// Errors holds "<host>: <error>" entries for routes whose backends
+	// couldn't be built from the reloaded config (e.g. an unreadable TLS
+	// file), and any error opening a new listener. Affected routes or the
+	// listener keep running their previous state untouched.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Reload applies the backend lists, strategy, and hash key from newCfg's
+// routes to the matching running routes, swapping each route's state
+// atomically. Routes present in newCfg but not already configured are
+// recorded in the diff but not created, since the routes map is read
+// without a lock on the request path; bringing up a genuinely new host
+// requires a restart.
+//
+// If newCfg.Server.ListenAddress differs from the running address, Reload
+// also opens a new listener and fasthttp.Server on it and serves new
+// connections there from a separate goroutine, while the previous server
+// drains its in-flight requests in the background via Shutdown. Start's
+// Serve call on the previous server returns once that server has shut down.
+//
+// It also applies newCfg's Server and Logging settings wholesale, so e.g.
+// a new NotFoundBody or Logging.Enabled takes effect immediately.
+func (p *Proxy) Reload(newCfg *config.Config) *ReloadDiff {
+	diff := &ReloadDiff{}
+
+	p.adminMu.Lock()
+	defer p.adminMu.Unlock()
+
+	for host, routeCfg := range newCfg.Routes {
+		rt := p.routeByHost(host)
+		if rt == nil {
+			diff.RoutesAdded = append(diff.RoutesAdded, host)
+			continue
+		}
+
+		old := rt.snapshot()
+		newState, err := buildRouteState(routeCfg, newCfg.Server, newCfg.PreWarm, p.logger, p.metrics)
+		if err != nil {
+			diff.Errors = append(diff.Errors, fmt.Sprintf("%s: %s", host, err))
+			continue
+		}
+
+		rt.state.Store(newState)
+		closeRemovedBackends(old, newState)
+
+		diff.RoutesChanged = append(diff.RoutesChanged, host)
+	}
+
+	if newCfg.Server.ListenAddress != p.config.Server.ListenAddress {
+		if err := p.rebindListener(newCfg.Server.ListenAddress); err != nil {
+			diff.Errors = append(diff.Errors, fmt.Sprintf("listener: %s", err))
+		} else {
+			diff.ListenerRebound = true
+		}
+	}
+
+	p.config.Routes = newCfg.Routes
+	p.config.Server = newCfg.Server
+	p.config.Logging = newCfg.Logging
+	p.requestConfig.Store(snapshotRequestConfig(p.config))
+
+	return diff
+}
+
+// closeRemovedBackends closes idle connections for every backend in old.
+// buildRouteState always constructs a brand-new HostClient for updated, even
+// for addresses that are unchanged, so old's clients are replaced wholesale
+// and must all be closed here rather than just the ones absent from updated.
+func closeRemovedBackends(old, _ *routeState) {
+	for _, target := range old.byAddress {
+		target.client.CloseIdleConnections()
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
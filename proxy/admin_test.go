@@ -0,0 +1,317 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidalecrim/extreme/config"
+	"github.com/valyala/fasthttp"
+)
+
+func newAdminTestProxy(t *testing.T, backends ...config.BackendConfig) *Proxy {
+	t.Helper()
+
+	if len(backends) == 0 {
+		backends = []config.BackendConfig{{Address: "unix:///tmp/admin-test.sock"}}
+	}
+
+	routeCfg := config.RouteConfig{Backends: backends}
+	st, err := buildRouteState(routeCfg, config.ServerConfig{}, config.PreWarmConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	if err != nil {
+		t.Fatalf("buildRouteState: %v", err)
+	}
+
+	fallback := &route{host: config.FallbackHost}
+	fallback.state.Store(st)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{},
+		Routes: config.RoutesConfig{config.FallbackHost: routeCfg},
+	}
+
+	p := &Proxy{
+		config:   cfg,
+		routes:   map[string]*route{},
+		fallback: fallback,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	p.requestConfig.Store(snapshotRequestConfig(cfg))
+	return p
+}
+
+func TestRequireTokenRejectsMissingOrWrongToken(t *testing.T) {
+	p := newAdminTestProxy(t)
+	p.config.Admin.Token = "s3cr3t"
+
+	called := false
+	handler := p.requireToken(func(http.ResponseWriter, *http.Request) { called = true })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with no Authorization header, got %d", rec.Code)
+	}
+	if called {
+		t.Fatal("expected next not to be called without a valid token")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with the wrong token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the correct token to pass through, got %d", rec.Code)
+	}
+	if !called {
+		t.Fatal("expected next to be called with a valid token")
+	}
+}
+
+func TestHandleGetConfigReturnsCurrentRoutes(t *testing.T) {
+	p := newAdminTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	rec := httptest.NewRecorder()
+	p.handleGetConfig(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/admin/config = %d, want 200", rec.Code)
+	}
+
+	var got config.Config
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := got.Routes[config.FallbackHost]; !ok {
+		t.Fatalf("expected the fallback route in the encoded config, got %+v", got.Routes)
+	}
+}
+
+func TestHandlePutBackendsRejectsEmptyBackends(t *testing.T) {
+	p := newAdminTestProxy(t)
+
+	body, _ := json.Marshal(backendsRequest{Host: config.FallbackHost, Backends: nil})
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/backends", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	p.handlePutBackends(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty backend list, got %d", rec.Code)
+	}
+}
+
+func TestHandlePutBackendsReplacesRouteState(t *testing.T) {
+	p := newAdminTestProxy(t)
+
+	body, _ := json.Marshal(backendsRequest{
+		Host:     config.FallbackHost,
+		Backends: []config.BackendConfig{{Address: "unix:///tmp/replaced.sock"}},
+	})
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/backends", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	p.handlePutBackends(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT /api/admin/backends = %d, want 200", rec.Code)
+	}
+
+	st := p.fallback.snapshot()
+	if _, ok := st.byAddress["unix:///tmp/replaced.sock"]; !ok {
+		t.Fatalf("expected the route's backends to be replaced, got %+v", st.byAddress)
+	}
+}
+
+// TestCloseRemovedBackendsClosesUnchangedAddressesToo exercises the bug
+// closeRemovedBackends used to have: buildRouteState always constructs a
+// fresh HostClient for every backend, even ones whose address didn't
+// change, so the old client for an unchanged address must still be closed
+// rather than left open just because updated also has that address.
+func TestCloseRemovedBackendsClosesUnchangedAddressesToo(t *testing.T) {
+	backend := newFakeBackend(t, "same-address", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+	old := newTestRoute(config.FallbackHost, backend).snapshot()
+
+	// A fresh client for the same address, as buildRouteState would produce
+	// on reload even though the address itself is unchanged.
+	updated := newTestRoute(config.FallbackHost, newFakeBackend(t, "same-address", 0, 0, nil)).snapshot()
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	req.SetRequestURI("/")
+	req.SetHost(backend.client.Addr)
+	if err := backend.client.Do(req, resp); err != nil {
+		t.Fatalf("priming old client's connection pool: %v", err)
+	}
+	fasthttp.ReleaseRequest(req)
+	fasthttp.ReleaseResponse(resp)
+
+	if backend.client.ConnsCount() == 0 {
+		t.Fatal("expected the old client to have an open pooled connection before closeRemovedBackends runs")
+	}
+
+	closeRemovedBackends(old, updated)
+
+	if got := backend.client.ConnsCount(); got != 0 {
+		t.Fatalf("expected closeRemovedBackends to close the old client's connection even though its address is still present in updated, got %d open", got)
+	}
+}
+
+func TestHandleDrainBackendMarksDraining(t *testing.T) {
+	p := newAdminTestProxy(t, config.BackendConfig{Address: "unix:///tmp/admin-test.sock"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/backends/x/drain", nil)
+	req.SetPathValue("addr", "unix:///tmp/admin-test.sock")
+	rec := httptest.NewRecorder()
+	p.handleDrainBackend(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 when draining a known backend, got %d", rec.Code)
+	}
+
+	target := p.fallback.snapshot().byAddress["unix:///tmp/admin-test.sock"]
+	if !target.draining.Load() {
+		t.Fatal("expected the backend to be marked draining")
+	}
+}
+
+// TestAdminAPIRaceOnConcurrentGetAndPutBackends exercises handleGetConfig
+// and handlePutBackends concurrently under the race detector: handleGetConfig
+// must not read p.config.Routes after releasing adminMu while a concurrent
+// PUT writes to it.
+func TestAdminAPIRaceOnConcurrentGetAndPutBackends(t *testing.T) {
+	p := newAdminTestProxy(t)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+				rec := httptest.NewRecorder()
+				p.handleGetConfig(rec, req)
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			body, _ := json.Marshal(backendsRequest{
+				Host:     config.FallbackHost,
+				Backends: []config.BackendConfig{{Address: "unix:///tmp/admin-test.sock"}},
+			})
+			req := httptest.NewRequest(http.MethodPut, "/api/admin/backends", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			p.handlePutBackends(rec, req)
+		}
+		close(stop)
+	}()
+
+	wg.Wait()
+}
+
+// TestReloadAppliesServerAndLoggingSettings checks that Reload's
+// requestConfig snapshot, not just p.config, picks up a reloaded
+// NotFoundBody and Logging.Enabled.
+func TestReloadAppliesServerAndLoggingSettings(t *testing.T) {
+	p := newAdminTestProxy(t)
+
+	newCfg := &config.Config{
+		Server:  config.ServerConfig{NotFoundBody: "reloaded not found"},
+		Logging: config.LoggingConfig{Enabled: true},
+		Routes:  p.config.Routes,
+	}
+
+	p.Reload(newCfg)
+
+	rc := p.requestConfig.Load()
+	if rc.notFoundBody != "reloaded not found" {
+		t.Fatalf("expected reloaded NotFoundBody to reach requestConfig, got %q", rc.notFoundBody)
+	}
+	if !rc.loggingEnabled {
+		t.Fatal("expected reloaded Logging.Enabled to reach requestConfig")
+	}
+}
+
+// TestShutdownRaceOnConcurrentReload exercises Shutdown and Reload
+// concurrently under the race detector: Shutdown must not read
+// p.config.Logging directly while a concurrent Reload (as a SIGHUP handler
+// or the /api/admin/reload endpoint would trigger) replaces p.config.Logging
+// wholesale.
+func TestShutdownRaceOnConcurrentReload(t *testing.T) {
+	p := newAdminTestProxy(t)
+
+	ln, err := listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	p.server = &fasthttp.Server{Handler: p.handleRequest}
+	p.listener = ln
+	go func() {
+		_ = p.server.Serve(ln)
+	}()
+
+	var wg sync.WaitGroup
+	var reloads atomic.Int32
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				p.Reload(&config.Config{
+					Server:  p.config.Server,
+					Routes:  p.config.Routes,
+					Logging: config.LoggingConfig{Enabled: true},
+				})
+				reloads.Add(1)
+			}
+		}
+	}()
+
+	// Give Reload a head start so Shutdown genuinely overlaps with it
+	// instead of racing an empty goroutine that hasn't scheduled yet.
+	for reloads.Load() < 50 {
+		time.Sleep(time.Microsecond)
+	}
+
+	if err := p.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/davidalecrim/extreme/config"
+	"github.com/valyala/fasthttp"
+)
+
+// dialTimeout bounds how long connecting to a backend may take, for every
+// scheme.
+const dialTimeout = 5 * time.Second
+
+// clientForBackend builds the dial function, TLS settings, and effective
+// address fasthttp.HostClient needs for b.Address, based on its scheme:
+// "unix://" (or a bare path, for backward compatibility), "tcp://", or
+// "https://".
+func clientForBackend(b config.BackendConfig) (addr string, dial fasthttp.DialFunc, isTLS bool, tlsConfig *tls.Config, err error) {
+	scheme, target := config.ParseBackendAddress(b.Address)
+
+	switch scheme {
+	case config.SchemeTCP:
+		return target, tcpDialer(target), false, nil, nil
+
+	case config.SchemeHTTPS:
+		tlsConfig, err = buildTLSConfig(b.TLS)
+		if err != nil {
+			return "", nil, false, nil, fmt.Errorf("backend %q: %w", b.Address, err)
+		}
+		return target, tcpDialer(target), true, tlsConfig, nil
+
+	case config.SchemeUnix:
+		return target, unixDialer(target), false, nil, nil
+
+	default:
+		return "", nil, false, nil, fmt.Errorf("backend %q: unsupported scheme %q", b.Address, scheme)
+	}
+}
+
+// matchRequestScheme sets req's URI scheme to match isTLS. HostClient.Do
+// and DoTimeout hard-fail with ErrHostClientRedirectToDifferentScheme
+// whenever c.IsTLS disagrees with the request's URI scheme, but callers
+// build requests with SetRequestURI("/path"), which always defaults to
+// "http", so every caller that dials a backendTarget must call this first.
+func matchRequestScheme(req *fasthttp.Request, isTLS bool) {
+	if isTLS {
+		req.URI().SetScheme("https")
+	} else {
+		req.URI().SetScheme("http")
+	}
+}
+
+func unixDialer(target string) fasthttp.DialFunc {
+	return func(string) (net.Conn, error) {
+		return net.DialTimeout("unix", target, dialTimeout)
+	}
+}
+
+func tcpDialer(target string) fasthttp.DialFunc {
+	return func(string) (net.Conn, error) {
+		return net.DialTimeout("tcp", target, dialTimeout)
+	}
+}
+
+// buildTLSConfig translates a BackendTLSConfig into a *tls.Config,
+// loading the CA bundle and optional mTLS client certificate from disk.
+func buildTLSConfig(cfg config.BackendTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", cfg.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidalecrim/extreme/config"
+	"github.com/valyala/fasthttp"
+)
+
+func TestClientForBackendDefaultsBareAddressToUnix(t *testing.T) {
+	addr, _, isTLS, tlsConfig, err := clientForBackend(config.BackendConfig{Address: "/tmp/backend.sock"})
+	if err != nil {
+		t.Fatalf("clientForBackend: %v", err)
+	}
+	if addr != "/tmp/backend.sock" {
+		t.Fatalf("expected bare address to pass through unchanged, got %q", addr)
+	}
+	if isTLS || tlsConfig != nil {
+		t.Fatal("expected a unix-socket backend not to use TLS")
+	}
+}
+
+func TestClientForBackendParsesTCPScheme(t *testing.T) {
+	addr, _, isTLS, tlsConfig, err := clientForBackend(config.BackendConfig{Address: "tcp://127.0.0.1:9000"})
+	if err != nil {
+		t.Fatalf("clientForBackend: %v", err)
+	}
+	if addr != "127.0.0.1:9000" {
+		t.Fatalf("expected the scheme to be stripped, got %q", addr)
+	}
+	if isTLS || tlsConfig != nil {
+		t.Fatal("expected a tcp backend not to use TLS")
+	}
+}
+
+func TestClientForBackendBuildsTLSConfigForHTTPS(t *testing.T) {
+	addr, _, isTLS, tlsConfig, err := clientForBackend(config.BackendConfig{
+		Address: "https://backend.internal:443",
+		TLS:     config.BackendTLSConfig{ServerName: "backend.internal", InsecureSkipVerify: true},
+	})
+	if err != nil {
+		t.Fatalf("clientForBackend: %v", err)
+	}
+	if addr != "backend.internal:443" {
+		t.Fatalf("expected the scheme to be stripped, got %q", addr)
+	}
+	if !isTLS {
+		t.Fatal("expected an https backend to use TLS")
+	}
+	if tlsConfig == nil || tlsConfig.ServerName != "backend.internal" || !tlsConfig.InsecureSkipVerify {
+		t.Fatalf("expected the TLS config to carry the backend's settings, got %+v", tlsConfig)
+	}
+}
+
+func TestClientForBackendRejectsUnsupportedScheme(t *testing.T) {
+	if _, _, _, _, err := clientForBackend(config.BackendConfig{Address: "ftp://backend:21"}); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
+
+func TestBuildTLSConfigLoadsCABundle(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+
+	caFile := writeCAFile(t, ts)
+
+	tlsConfig, err := buildTLSConfig(config.BackendTLSConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	if tlsConfig.RootCAs == nil {
+		t.Fatal("expected RootCAs to be populated from CAFile")
+	}
+}
+
+func TestBuildTLSConfigRejectsUnreadableCAFile(t *testing.T) {
+	if _, err := buildTLSConfig(config.BackendTLSConfig{CAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Fatal("expected an error when the CA file can't be read")
+	}
+}
+
+func writeCAFile(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+	return caFile
+}
+
+// TestBuildBackendTargetHTTPSRoundTrip spins up a real httptest TLS server
+// and verifies buildBackendTarget produces a client that can reach it,
+// trusting only the CA bundle built from the test server's certificate.
+func TestBuildBackendTargetHTTPSRoundTrip(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	caFile := writeCAFile(t, ts)
+	addr := strings.TrimPrefix(ts.URL, "https://")
+
+	target, err := buildBackendTarget(
+		config.BackendConfig{Address: "https://" + addr, TLS: config.BackendTLSConfig{CAFile: caFile}},
+		config.ServerConfig{},
+		config.PreWarmConfig{},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("buildBackendTarget: %v", err)
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI("/")
+	req.SetHost(target.client.Addr) // target.address keeps the "https://" scheme; Addr is the dial-ready host:port
+	req.Header.SetMethod(fasthttp.MethodGet)
+	matchRequestScheme(req, target.client.IsTLS)
+
+	if err := target.client.Do(req, resp); err != nil {
+		t.Fatalf("request to TLS backend failed: %v", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode())
+	}
+	if string(resp.Body()) != "ok" {
+		t.Fatalf("expected body %q, got %q", "ok", resp.Body())
+	}
+}
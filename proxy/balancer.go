@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// Balancer selects which backend should serve the next request on a route.
+// Pick returns the chosen backend's address and a release callback that the
+// caller must invoke once the request to that backend has completed, so the
+// balancer can update whatever stats it uses to make future decisions.
+type Balancer interface {
+	Pick(ctx *fasthttp.RequestCtx) (backend string, release func(err error, latency time.Duration))
+}
+
+// Balancing strategy names accepted in RouteConfig.Strategy.
+const (
+	StrategyRoundRobin     = "round_robin"
+	StrategyLeastConn      = "least_conn"
+	StrategyEWMALatency    = "ewma_latency"
+	StrategyConsistentHash = "consistent_hash"
+)
+
+// newBalancer builds the Balancer for a route's strategy. An unknown or
+// empty strategy falls back to round-robin, matching the proxy's original
+// behavior. healthy reports whether a backend address should currently be
+// considered for picking; it may be nil, in which case every backend is
+// considered healthy (health checking is disabled).
+func newBalancer(routeCfg routeBalancerConfig, backends []string, healthy func(string) bool) Balancer {
+	if healthy == nil {
+		healthy = func(string) bool { return true }
+	}
+
+	switch routeCfg.Strategy {
+	case StrategyLeastConn:
+		return newLeastConnBalancer(backends, healthy)
+	case StrategyEWMALatency:
+		return newEWMABalancer(backends, healthy)
+	case StrategyConsistentHash:
+		return newConsistentHashBalancer(backends, routeCfg.HashKey, healthy)
+	default:
+		return newRoundRobinBalancer(backends, healthy)
+	}
+}
+
+// healthyIndices returns the indices of backends considered healthy. If
+// none are healthy, it returns every index so the route still attempts
+// delivery rather than failing outright.
+func healthyIndices(backends []string, healthy func(string) bool) []int {
+	indices := make([]int, 0, len(backends))
+	for i, addr := range backends {
+		if healthy(addr) {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		for i := range backends {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// routeBalancerConfig carries the balancer-related fields out of
+// config.RouteConfig so this file doesn't need to import config directly
+// for every helper.
+type routeBalancerConfig struct {
+	Strategy string
+	HashKey  string
+}
+
+// --- round-robin ---
+
+type roundRobinBalancer struct {
+	backends []string
+	healthy  func(string) bool
+	next     uint32
+}
+
+func newRoundRobinBalancer(backends []string, healthy func(string) bool) *roundRobinBalancer {
+	return &roundRobinBalancer{backends: backends, healthy: healthy}
+}
+
+func (b *roundRobinBalancer) Pick(_ *fasthttp.RequestCtx) (string, func(error, time.Duration)) {
+	indices := healthyIndices(b.backends, b.healthy)
+	n := atomic.AddUint32(&b.next, 1)
+	idx := indices[int(n)%len(indices)]
+	return b.backends[idx], noopRelease
+}
+
+// --- least-connections ---
+
+type leastConnBalancer struct {
+	backends []string
+	healthy  func(string) bool
+	inFlight []int64
+}
+
+func newLeastConnBalancer(backends []string, healthy func(string) bool) *leastConnBalancer {
+	return &leastConnBalancer{
+		backends: backends,
+		healthy:  healthy,
+		inFlight: make([]int64, len(backends)),
+	}
+}
+
+func (b *leastConnBalancer) Pick(_ *fasthttp.RequestCtx) (string, func(error, time.Duration)) {
+	indices := healthyIndices(b.backends, b.healthy)
+
+	best := indices[0]
+	for _, i := range indices[1:] {
+		if atomic.LoadInt64(&b.inFlight[i]) < atomic.LoadInt64(&b.inFlight[best]) {
+			best = i
+		}
+	}
+
+	atomic.AddInt64(&b.inFlight[best], 1)
+	return b.backends[best], func(error, time.Duration) {
+		atomic.AddInt64(&b.inFlight[best], -1)
+	}
+}
+
+// --- EWMA latency ---
+
+// ewmaAlpha controls how quickly the moving average reacts to new
+// latency samples; smaller values favor historical stability.
+const ewmaAlpha = 0.2
+
+type ewmaBalancer struct {
+	backends []string
+	healthy  func(string) bool
+	latency  []uint64 // float64 bits, nanoseconds
+}
+
+func newEWMABalancer(backends []string, healthy func(string) bool) *ewmaBalancer {
+	return &ewmaBalancer{
+		backends: backends,
+		healthy:  healthy,
+		latency:  make([]uint64, len(backends)),
+	}
+}
+
+func (b *ewmaBalancer) Pick(_ *fasthttp.RequestCtx) (string, func(error, time.Duration)) {
+	eligible := healthyIndices(b.backends, b.healthy)
+	candidates := make([]int, 0, len(eligible))
+	best := math.MaxFloat64
+
+	for _, i := range eligible {
+		l := math.Float64frombits(atomic.LoadUint64(&b.latency[i]))
+		switch {
+		case l < best:
+			best = l
+			candidates = candidates[:0]
+			candidates = append(candidates, i)
+		case l == best:
+			candidates = append(candidates, i)
+		}
+	}
+
+	idx := candidates[rand.IntN(len(candidates))]
+
+	return b.backends[idx], func(err error, latency time.Duration) {
+		if err != nil {
+			return
+		}
+		for {
+			old := atomic.LoadUint64(&b.latency[idx])
+			oldVal := math.Float64frombits(old)
+			newVal := oldVal
+			if oldVal == 0 {
+				newVal = float64(latency)
+			} else {
+				newVal = ewmaAlpha*float64(latency) + (1-ewmaAlpha)*oldVal
+			}
+			if atomic.CompareAndSwapUint64(&b.latency[idx], old, math.Float64bits(newVal)) {
+				return
+			}
+		}
+	}
+}
+
+// --- consistent hashing (Ketama-style) ---
+
+// vnodesPerBackend is the number of virtual nodes hashed onto the ring for
+// each real backend, smoothing out load distribution.
+const vnodesPerBackend = 160
+
+type consistentHashBalancer struct {
+	ring    []uint32
+	owners  map[uint32]string
+	hashKey string
+	healthy func(string) bool
+}
+
+func newConsistentHashBalancer(backends []string, hashKey string, healthy func(string) bool) *consistentHashBalancer {
+	b := &consistentHashBalancer{
+		owners:  make(map[uint32]string, len(backends)*vnodesPerBackend),
+		hashKey: hashKey,
+		healthy: healthy,
+	}
+
+	for _, addr := range backends {
+		for v := 0; v < vnodesPerBackend; v++ {
+			h := xxhash.Sum64String(addr + "#" + strconv.Itoa(v))
+			hash := uint32(h)
+			b.ring = append(b.ring, hash)
+			b.owners[hash] = addr
+		}
+	}
+
+	sort.Slice(b.ring, func(i, j int) bool { return b.ring[i] < b.ring[j] })
+
+	return b
+}
+
+func (b *consistentHashBalancer) Pick(ctx *fasthttp.RequestCtx) (string, func(error, time.Duration)) {
+	key := b.requestKey(ctx)
+	hash := uint32(xxhash.Sum64String(key))
+
+	start := sort.Search(len(b.ring), func(i int) bool { return b.ring[i] >= hash })
+	if start == len(b.ring) {
+		start = 0
+	}
+
+	// Walk the ring forward looking for a healthy owner, falling back to
+	// the originally resolved owner if every backend is ejected.
+	for i := 0; i < len(b.ring); i++ {
+		addr := b.owners[b.ring[(start+i)%len(b.ring)]]
+		if b.healthy(addr) {
+			return addr, noopRelease
+		}
+	}
+
+	return b.owners[b.ring[start]], noopRelease
+}
+
+// requestKey extracts the value used to place a request on the hash ring.
+// An empty or "path" HashKey hashes the request path; any other value is
+// treated as a header name.
+func (b *consistentHashBalancer) requestKey(ctx *fasthttp.RequestCtx) string {
+	if b.hashKey == "" || b.hashKey == "path" {
+		return string(ctx.Path())
+	}
+	return string(ctx.Request.Header.Peek(b.hashKey))
+}
+
+func noopRelease(error, time.Duration) {}
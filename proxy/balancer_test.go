@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func alwaysHealthy(string) bool { return true }
+
+func TestRoundRobinBalancerDistributesFairly(t *testing.T) {
+	backends := []string{"a", "b", "c", "d"}
+	b := newRoundRobinBalancer(backends, alwaysHealthy)
+
+	counts := make(map[string]int, len(backends))
+	const iterations = 4000
+
+	for i := 0; i < iterations; i++ {
+		addr, release := b.Pick(nil)
+		release(nil, 0)
+		counts[addr]++
+	}
+
+	want := iterations / len(backends)
+	for _, addr := range backends {
+		if got := counts[addr]; got != want {
+			t.Fatalf("backend %q got %d picks, want exactly %d for a perfectly round-robin distribution", addr, got, want)
+		}
+	}
+}
+
+func TestLeastConnBalancerPicksFewestInFlight(t *testing.T) {
+	backends := []string{"a", "b", "c"}
+	b := newLeastConnBalancer(backends, alwaysHealthy)
+
+	// Load up "a" and "b" with outstanding requests so only "c" is idle.
+	_, releaseA := b.Pick(nil)
+	_, releaseB := b.Pick(nil)
+	_ = releaseA
+	_ = releaseB
+
+	addr, release := b.Pick(nil)
+	release(nil, 0)
+
+	if addr != "c" {
+		t.Fatalf("expected the idle backend %q to be picked, got %q", "c", addr)
+	}
+}
+
+func TestEWMABalancerPrefersLowerLatency(t *testing.T) {
+	backends := []string{"slow", "fast"}
+	b := newEWMABalancer(backends, alwaysHealthy)
+
+	// Seed the moving averages directly so the test doesn't depend on the
+	// random tie-break Pick uses for backends with no samples yet.
+	atomic.StoreUint64(&b.latency[0], math.Float64bits(float64(100*time.Millisecond)))
+	atomic.StoreUint64(&b.latency[1], math.Float64bits(float64(time.Millisecond)))
+
+	var fastPicks int
+	const iterations = 50
+	for i := 0; i < iterations; i++ {
+		addr, release := b.Pick(nil)
+		release(nil, time.Millisecond)
+		if addr == "fast" {
+			fastPicks++
+		}
+	}
+
+	if fastPicks != iterations {
+		t.Fatalf("expected the lower-latency backend to be picked every time once its average is established, got %d/%d", fastPicks, iterations)
+	}
+}
+
+func TestConsistentHashBalancerStableUnderAddAndRemove(t *testing.T) {
+	original := []string{"backend-1", "backend-2", "backend-3", "backend-4"}
+	b := newConsistentHashBalancer(original, "path", alwaysHealthy)
+
+	keys := make([]string, 200)
+	owners := make(map[string]string, len(keys))
+	for i := range keys {
+		keys[i] = requestPath(i)
+		owners[keys[i]] = pickFor(t, b, keys[i])
+	}
+
+	t.Run("add a backend", func(t *testing.T) {
+		withAdded := newConsistentHashBalancer(append(append([]string{}, original...), "backend-5"), "path", alwaysHealthy)
+
+		remapped := 0
+		for _, key := range keys {
+			if pickFor(t, withAdded, key) != owners[key] {
+				remapped++
+			}
+		}
+
+		// Ketama-style consistent hashing should only remap a small
+		// fraction of keys when a single backend joins a pool of five.
+		if maxRemap := len(keys) / 2; remapped > maxRemap {
+			t.Fatalf("adding a backend remapped %d/%d keys, expected at most %d", remapped, len(keys), maxRemap)
+		}
+	})
+
+	t.Run("remove a backend", func(t *testing.T) {
+		withRemoved := newConsistentHashBalancer(original[:len(original)-1], "path", alwaysHealthy)
+
+		remapped := 0
+		for _, key := range keys {
+			if pickFor(t, withRemoved, key) != owners[key] {
+				remapped++
+			}
+		}
+
+		if maxRemap := len(keys) / 2; remapped > maxRemap {
+			t.Fatalf("removing a backend remapped %d/%d keys, expected at most %d", remapped, len(keys), maxRemap)
+		}
+	})
+}
+
+func requestPath(i int) string {
+	return "/resource/" + string(rune('a'+i%26)) + string(rune('0'+(i/26)%10))
+}
+
+func pickFor(t *testing.T, b *consistentHashBalancer, path string) string {
+	t.Helper()
+
+	var req fasthttp.Request
+	req.SetRequestURI("http://example.com" + path)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+
+	addr, _ := b.Pick(&ctx)
+	return addr
+}
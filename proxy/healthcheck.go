@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/davidalecrim/extreme/config"
+	"github.com/davidalecrim/extreme/observability"
+	"github.com/valyala/fasthttp"
+)
+
+// healthChecker periodically probes a set of backends over their existing
+// fasthttp.HostClient pool and ejects or reinstates them based on
+// consecutive probe outcomes.
+type healthChecker struct {
+	cfg     config.HealthCheckConfig
+	targets func() []*backendTarget
+	logger  *slog.Logger
+	metrics *observability.Metrics
+	stop    chan struct{}
+}
+
+// newHealthChecker builds a checker that probes whatever backends targets
+// returns at the start of each interval, so backends swapped in later by
+// the admin API are picked up automatically.
+func newHealthChecker(cfg config.HealthCheckConfig, targets func() []*backendTarget, logger *slog.Logger, metrics *observability.Metrics) *healthChecker {
+	return &healthChecker{
+		cfg:     cfg,
+		targets: targets,
+		logger:  logger,
+		metrics: metrics,
+		stop:    make(chan struct{}),
+	}
+}
+
+// run probes every target once per interval until stop is closed. The
+// first probe happens after one interval has elapsed, not immediately, so
+// backends are never considered unhealthy before they've been probed at
+// least once.
+func (h *healthChecker) run() {
+	ticker := time.NewTicker(h.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, target := range h.targets() {
+				h.probe(target)
+			}
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+func (h *healthChecker) probe(target *backendTarget) {
+	method := h.cfg.Method
+	if method == "" {
+		method = fasthttp.MethodHead
+	}
+
+	expectedStatus := h.cfg.ExpectedStatus
+	if expectedStatus == 0 {
+		expectedStatus = fasthttp.StatusOK
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(h.cfg.Path)
+	req.SetHost(target.client.Addr)
+	req.Header.SetMethod(method)
+	matchRequestScheme(req, target.client.IsTLS)
+
+	err := target.client.DoTimeout(req, resp, h.cfg.Timeout)
+	healthy := err == nil && resp.StatusCode() == expectedStatus
+
+	if healthy {
+		target.consecutiveFailures.Store(0)
+		successes := target.consecutiveSuccesses.Add(1)
+
+		if target.ejected.Load() && successes >= int32(h.cfg.HealthyThreshold) {
+			target.ejected.Store(false)
+			h.logger.Info("backend recovered", "backend", target.address)
+			h.setEjectedMetric(target.address, false)
+		}
+		return
+	}
+
+	target.consecutiveSuccesses.Store(0)
+	failures := target.consecutiveFailures.Add(1)
+
+	if !target.ejected.Load() && failures >= int32(h.cfg.UnhealthyThreshold) {
+		target.ejected.Store(true)
+		h.logger.Warn("backend ejected",
+			"backend", target.address,
+			"error", err,
+			"status", resp.StatusCode(),
+		)
+		h.setEjectedMetric(target.address, true)
+	}
+}
+
+func (h *healthChecker) setEjectedMetric(address string, ejected bool) {
+	if h.metrics == nil {
+		return
+	}
+	value := 0.0
+	if ejected {
+		value = 1.0
+	}
+	h.metrics.BackendEjected.WithLabelValues(address).Set(value)
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidalecrim/extreme/config"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func newTestHealthChecker(cfg config.HealthCheckConfig) (*healthChecker, *atomic.Bool, *backendTarget) {
+	var up atomic.Bool
+	up.Store(true)
+
+	ln := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		if up.Load() {
+			ctx.SetStatusCode(fasthttp.StatusOK)
+		} else {
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		}
+	}}
+	go func() { _ = server.Serve(ln) }()
+
+	client := &fasthttp.HostClient{
+		Addr: "fake-backend",
+		Dial: func(string) (net.Conn, error) { return ln.Dial() },
+	}
+
+	target := &backendTarget{address: "fake-backend", client: client}
+
+	hc := newHealthChecker(cfg, func() []*backendTarget { return []*backendTarget{target} },
+		slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	return hc, &up, target
+}
+
+func TestHealthCheckerDefaultsExpectedStatusTo200(t *testing.T) {
+	hc, _, target := newTestHealthChecker(config.HealthCheckConfig{
+		Path:               "/healthz",
+		UnhealthyThreshold: 1,
+		HealthyThreshold:   1,
+		Timeout:            time.Second,
+		// ExpectedStatus intentionally left at its zero value.
+	})
+
+	hc.probe(target)
+
+	if target.ejected.Load() {
+		t.Fatal("expected a 200 response to be considered healthy when ExpectedStatus is unset")
+	}
+}
+
+func TestHealthCheckerEjectsAfterUnhealthyThreshold(t *testing.T) {
+	hc, up, target := newTestHealthChecker(config.HealthCheckConfig{
+		Path:               "/healthz",
+		ExpectedStatus:     fasthttp.StatusOK,
+		UnhealthyThreshold: 2,
+		HealthyThreshold:   2,
+		Timeout:            time.Second,
+	})
+
+	up.Store(false)
+
+	hc.probe(target)
+	if target.ejected.Load() {
+		t.Fatal("backend should not be ejected before crossing UnhealthyThreshold")
+	}
+
+	hc.probe(target)
+	if !target.ejected.Load() {
+		t.Fatal("expected backend to be ejected after 2 consecutive unhealthy probes")
+	}
+}
+
+func TestHealthCheckerRecoversAfterHealthyThreshold(t *testing.T) {
+	hc, up, target := newTestHealthChecker(config.HealthCheckConfig{
+		Path:               "/healthz",
+		ExpectedStatus:     fasthttp.StatusOK,
+		UnhealthyThreshold: 1,
+		HealthyThreshold:   2,
+		Timeout:            time.Second,
+	})
+
+	up.Store(false)
+	hc.probe(target)
+	if !target.ejected.Load() {
+		t.Fatal("expected backend to be ejected after crossing UnhealthyThreshold")
+	}
+
+	up.Store(true)
+	hc.probe(target)
+	if !target.ejected.Load() {
+		t.Fatal("backend should still be ejected before crossing HealthyThreshold")
+	}
+
+	hc.probe(target)
+	if target.ejected.Load() {
+		t.Fatal("expected backend to recover after 2 consecutive healthy probes")
+	}
+}
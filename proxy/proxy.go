@@ -1,160 +1,593 @@
 // Package proxy implements a high-performance HTTP reverse proxy using fasthttp.
 // It provides efficient request forwarding with minimal overhead, connection pooling,
-// and round-robin load balancing across multiple backend servers.
+// and host-based routing with a per-backend retry policy.
 package proxy
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/davidalecrim/extreme/config"
+	"github.com/davidalecrim/extreme/observability"
 	"github.com/valyala/fasthttp"
 )
 
+// backendTarget pairs a configured backend with the pooled client used to
+// reach it and the state the health checker and admin API use to pull it
+// out of rotation.
+type backendTarget struct {
+	address string
+	client  *fasthttp.HostClient
+	retries int
+	delay   time.Duration
+	timeout time.Duration
+
+	ejected              atomic.Bool
+	consecutiveFailures  atomic.Int32
+	consecutiveSuccesses atomic.Int32
+	draining             atomic.Bool
+}
+
+// eligible reports whether this backend should currently receive new
+// requests: it isn't ejected by the health checker and isn't draining.
+func (b *backendTarget) eligible() bool {
+	return !b.ejected.Load() && !b.draining.Load()
+}
+
+// routeState is the immutable set of backends and Balancer for a route at
+// a point in time. Routes swap their state atomically so handleRequest
+// never blocks on a lock while the admin API mutates backends.
+type routeState struct {
+	backends  []*backendTarget
+	byAddress map[string]*backendTarget
+	balancer  Balancer
+	strategy  string
+	hashKey   string
+}
+
+// route holds the current state of the backends that serve a given host.
+type route struct {
+	host  string
+	state atomic.Pointer[routeState]
+}
+
+// snapshot returns the route's current backend state.
+func (r *route) snapshot() *routeState {
+	return r.state.Load()
+}
+
 // Proxy represents a high-performance reverse proxy server.
-// It handles incoming HTTP requests and forwards them to configured backend servers
-// using connection pooling and load balancing for optimal performance.
+// It handles incoming HTTP requests and forwards them to the backends
+// configured for the request's Host header, retrying across backends
+// according to each backend's retry policy.
 type Proxy struct {
-	config         *config.Config
-	clients        map[string]*fasthttp.HostClient
-	currentBackend uint32
-	server         *fasthttp.Server
-	logger         *slog.Logger
+	config   *config.Config
+	routes   map[string]*route
+	fallback *route
+	logger   *slog.Logger
+	metrics  *observability.Metrics
+	stopPool chan struct{}
+	health   *healthChecker
+	adminMu  sync.Mutex
+
+	// requestConfig holds the Config fields handleRequest reads on the hot
+	// path, swapped atomically so Reload can update Server/Logging without
+	// handleRequest ever racing on p.config's fields directly.
+	requestConfig atomic.Pointer[requestConfig]
+
+	// serverMu guards server and listener, which Reload swaps when
+	// ListenAddress changes. The hot request path never touches it.
+	serverMu sync.Mutex
+	server   *fasthttp.Server
+	listener net.Listener
+}
+
+// requestConfig is the subset of config.Config that handleRequest, Start,
+// Shutdown, and rebindListener read without holding adminMu.
+type requestConfig struct {
+	notFoundBody   string
+	loggingEnabled bool
+	listenAddress  string
 }
 
+// snapshotRequestConfig builds a requestConfig from cfg's current Server and
+// Logging settings.
+func snapshotRequestConfig(cfg *config.Config) *requestConfig {
+	return &requestConfig{
+		notFoundBody:   cfg.Server.NotFoundBody,
+		loggingEnabled: cfg.Logging.Enabled,
+		listenAddress:  cfg.Server.ListenAddress,
+	}
+}
+
+// poolMetricsInterval is how often the pool-connection gauge is refreshed
+// from each backend's fasthttp.HostClient.
+const poolMetricsInterval = 5 * time.Second
+
 // New creates and returns a new Proxy instance configured with the provided
-// configuration and logger. It sets up the fasthttp client and server with
-// optimized settings for high performance and minimal latency.
-func New(cfg *config.Config, logger *slog.Logger) (*Proxy, error) {
-	clients := make(map[string]*fasthttp.HostClient, len(cfg.BackendSockets))
-
-	for _, backend := range cfg.BackendSockets {
-		client := &fasthttp.HostClient{
-			Addr: backend,
-			Dial: func(addr string) (net.Conn, error) {
-				return net.DialTimeout("unix", addr, 5*time.Second)
-			},
-			MaxIdleConnDuration: cfg.Server.KeepAliveTimeout,
-			ReadTimeout:         cfg.Server.ReadTimeout,
-			WriteTimeout:        cfg.Server.WriteTimeout,
-
-			NoDefaultUserAgentHeader:      true,
-			DisablePathNormalizing:        true,
-			DisableHeaderNamesNormalizing: true,
-		}
-
-		if cfg.PreWarm.Enabled {
-			preWarmCount := cfg.PreWarm.RequestsPerBackend
-			for range preWarmCount {
-				go func() {
-					// Create a dummy request to establish connection and keep it alive
-					req := fasthttp.AcquireRequest()
-					resp := fasthttp.AcquireResponse()
-
-					req.SetRequestURI("/")
-					req.SetHost(client.Addr) // dummy host because of unix sockets
-					req.Header.SetMethod(fasthttp.MethodHead)
-
-					if err := client.Do(req, resp); err != nil {
-						logger.Warn("failed to pre-warm connection",
-							"backend", backend,
-							"error", err,
-						)
-					}
-
-					fasthttp.ReleaseRequest(req)
-					fasthttp.ReleaseResponse(resp)
-				}()
-			}
+// configuration and logger. It sets up the fasthttp clients and server with
+// optimized settings for high performance and minimal latency. metrics may
+// be nil, in which case the proxy runs without instrumentation.
+func New(cfg *config.Config, logger *slog.Logger, metrics *observability.Metrics) (*Proxy, error) {
+	routes := make(map[string]*route, len(cfg.Routes))
+	var fallback *route
+
+	for host, routeCfg := range cfg.Routes {
+		st, err := buildRouteState(routeCfg, cfg.Server, cfg.PreWarm, logger, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", host, err)
 		}
 
-		clients[backend] = client
+		r := &route{host: host}
+		r.state.Store(st)
+
+		if host == config.FallbackHost {
+			fallback = r
+			continue
+		}
+
+		routes[host] = r
 	}
 
 	p := &Proxy{
-		config:  cfg,
-		clients: clients,
-		logger:  logger,
+		config:   cfg,
+		routes:   routes,
+		fallback: fallback,
+		logger:   logger,
+		metrics:  metrics,
 	}
+	p.requestConfig.Store(snapshotRequestConfig(cfg))
 
 	p.server = &fasthttp.Server{
 		Handler: p.handleRequest,
 	}
 
+	if metrics != nil {
+		p.stopPool = make(chan struct{})
+		go p.reportPoolMetrics()
+	}
+
+	if cfg.HealthCheck.Enabled {
+		p.health = newHealthChecker(cfg.HealthCheck, p.allBackends, logger, metrics)
+		go p.health.run()
+	}
+
 	return p, nil
 }
 
+// buildBackendTarget constructs the pooled client and retry policy for a
+// single configured backend, pre-warming its connection pool if enabled. It
+// returns an error if b.Address names an unsupported scheme or its TLS
+// settings can't be loaded.
+func buildBackendTarget(b config.BackendConfig, serverCfg config.ServerConfig, preWarm config.PreWarmConfig, logger *slog.Logger, metrics *observability.Metrics) (*backendTarget, error) {
+	addr, dial, isTLS, tlsConfig, err := clientForBackend(b)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &fasthttp.HostClient{
+		Addr:                addr,
+		Dial:                dial,
+		IsTLS:               isTLS,
+		TLSConfig:           tlsConfig,
+		MaxIdleConnDuration: serverCfg.KeepAliveTimeout,
+		ReadTimeout:         serverCfg.ReadTimeout,
+		WriteTimeout:        serverCfg.WriteTimeout,
+
+		NoDefaultUserAgentHeader:      true,
+		DisablePathNormalizing:        true,
+		DisableHeaderNamesNormalizing: true,
+	}
+
+	if preWarm.Enabled {
+		preWarmConnections(client, b.Address, preWarm.RequestsPerBackend, logger, metrics)
+	}
+
+	return &backendTarget{
+		address: b.Address,
+		client:  client,
+		retries: b.Retries,
+		delay:   b.Delay,
+		timeout: b.Timeout,
+	}, nil
+}
+
+// buildRouteState builds a fresh routeState (backends, clients, and
+// Balancer) for a route's configuration. It's used both at startup and by
+// the admin API when backends are replaced at runtime.
+func buildRouteState(routeCfg config.RouteConfig, serverCfg config.ServerConfig, preWarm config.PreWarmConfig, logger *slog.Logger, metrics *observability.Metrics) (*routeState, error) {
+	if len(routeCfg.Backends) == 0 {
+		return nil, fmt.Errorf("route has no backends configured")
+	}
+
+	st := &routeState{
+		backends:  make([]*backendTarget, 0, len(routeCfg.Backends)),
+		byAddress: make(map[string]*backendTarget, len(routeCfg.Backends)),
+		strategy:  routeCfg.Strategy,
+		hashKey:   routeCfg.HashKey,
+	}
+
+	addresses := make([]string, 0, len(routeCfg.Backends))
+
+	for _, b := range routeCfg.Backends {
+		target, err := buildBackendTarget(b, serverCfg, preWarm, logger, metrics)
+		if err != nil {
+			return nil, err
+		}
+		st.backends = append(st.backends, target)
+		st.byAddress[b.Address] = target
+		addresses = append(addresses, b.Address)
+	}
+
+	isHealthy := func(addr string) bool {
+		target, ok := st.byAddress[addr]
+		return !ok || target.eligible()
+	}
+
+	st.balancer = newBalancer(routeBalancerConfig{
+		Strategy: routeCfg.Strategy,
+		HashKey:  routeCfg.HashKey,
+	}, addresses, isHealthy)
+
+	return st, nil
+}
+
+// reportPoolMetrics periodically refreshes the per-backend open-connection
+// gauge from each fasthttp.HostClient's pool until Shutdown is called.
+func (p *Proxy) reportPoolMetrics() {
+	ticker := time.NewTicker(poolMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, backend := range p.allBackends() {
+				p.metrics.PoolOpenConns.WithLabelValues(backend.address).Set(float64(backend.client.ConnsCount()))
+			}
+		case <-p.stopPool:
+			return
+		}
+	}
+}
+
+// preWarmConnections issues a handful of dummy requests against client so its
+// idle connection pool is warm before real traffic arrives.
+func preWarmConnections(client *fasthttp.HostClient, backend string, count int, logger *slog.Logger, metrics *observability.Metrics) {
+	for range count {
+		go func() {
+			// Create a dummy request to establish connection and keep it alive
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+
+			req.SetRequestURI("/")
+			req.SetHost(client.Addr) // dummy host; value doesn't matter, client.Addr is what's actually dialed
+			req.Header.SetMethod(fasthttp.MethodHead)
+			matchRequestScheme(req, client.IsTLS)
+
+			outcome := "success"
+			if err := client.Do(req, resp); err != nil {
+				outcome = "failure"
+				logger.Warn("failed to pre-warm connection",
+					"backend", backend,
+					"error", err,
+				)
+			}
+
+			if metrics != nil {
+				metrics.PreWarmTotal.WithLabelValues(backend, outcome).Inc()
+			}
+
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+		}()
+	}
+}
+
 func (p *Proxy) handleRequest(ctx *fasthttp.RequestCtx) {
-	backend := p.getNextBackend()
-	client := p.clients[backend]
+	rc := p.requestConfig.Load()
 
-	if p.config.Logging.Enabled {
-		p.logger.Debug("forwarding request",
-			"backend", backend,
-			"method", string(ctx.Method()),
-			"path", string(ctx.Path()),
-		)
+	r := p.routeFor(string(ctx.Host()))
+	if r == nil {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.SetBodyString(rc.notFoundBody)
+		return
 	}
 
+	state := r.snapshot()
+
 	req := &ctx.Request
 	resp := &ctx.Response
+	method := string(ctx.Method())
 
-	if err := client.Do(req, resp); err != nil {
-		if p.config.Logging.Enabled {
-			p.logger.Error("error forwarding request",
-				"error", err,
-				"backend", backend,
-				"request", map[string]any{
-					"method": string(ctx.Method()),
-					"path":   string(ctx.Path()),
-				},
-			)
-		}
+	var lastErr error
+	var lastBackend string
+
+	reqStart := time.Now()
+
+	if p.metrics != nil {
+		p.metrics.InFlight.WithLabelValues(r.host).Inc()
+		defer p.metrics.InFlight.WithLabelValues(r.host).Dec()
+	}
+
+	picked, release := state.balancer.Pick(ctx)
+	order := state.orderFrom(picked)
+
+	if len(order) == 0 {
 		ctx.SetStatusCode(fasthttp.StatusBadGateway)
 		ctx.SetBodyString("Gateway Error")
 		return
 	}
+
+	// The picked backend's Retries bounds how many additional, different
+	// backends get tried after it fails, not how many times it itself is
+	// retried: each backend in order is tried at most once.
+	maxAttempts := len(order)
+	if budget := order[0].retries + 1; budget < maxAttempts {
+		maxAttempts = budget
+	}
+
+	for i := 0; i < maxAttempts; i++ {
+		backend := order[i]
+		lastBackend = backend.address
+
+		if rc.loggingEnabled {
+			p.logger.Debug("forwarding request",
+				"host", r.host,
+				"backend", backend.address,
+				"method", method,
+				"path", string(ctx.Path()),
+			)
+		}
+
+		start := time.Now()
+
+		if p.metrics != nil {
+			p.metrics.InFlight.WithLabelValues(backend.address).Inc()
+		}
+
+		matchRequestScheme(req, backend.client.IsTLS)
+		if backend.timeout > 0 {
+			lastErr = backend.client.DoTimeout(req, resp, backend.timeout)
+		} else {
+			lastErr = backend.client.Do(req, resp)
+		}
+
+		if p.metrics != nil {
+			p.metrics.InFlight.WithLabelValues(backend.address).Dec()
+		}
+
+		if i == 0 {
+			release(lastErr, time.Since(start))
+		}
+
+		if lastErr == nil && resp.StatusCode() < fasthttp.StatusInternalServerError {
+			p.observeRequest(backend.address, method, resp.StatusCode(), reqStart)
+			return
+		}
+
+		if rc.loggingEnabled {
+			p.logger.Warn("backend attempt failed",
+				"host", r.host,
+				"backend", backend.address,
+				"error", lastErr,
+				"status", resp.StatusCode(),
+			)
+		}
+
+		if p.metrics != nil {
+			p.metrics.UpstreamErrors.WithLabelValues(backend.address).Inc()
+		}
+
+		if i < maxAttempts-1 {
+			time.Sleep(backend.delay)
+		}
+	}
+
+	if rc.loggingEnabled {
+		p.logger.Error("all backends exhausted for request",
+			"host", r.host,
+			"method", method,
+			"path", string(ctx.Path()),
+			"error", lastErr,
+		)
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusBadGateway)
+	ctx.SetBodyString("Gateway Error")
+	p.observeRequest(lastBackend, method, fasthttp.StatusBadGateway, reqStart)
+}
+
+// observeRequest records the request-duration histogram and request
+// counter for a completed request. It is a no-op when metrics are
+// disabled.
+func (p *Proxy) observeRequest(backend, method string, statusCode int, start time.Time) {
+	if p.metrics == nil {
+		return
+	}
+
+	statusClass := observability.StatusClass(statusCode)
+	p.metrics.RequestDuration.WithLabelValues(backend, method, statusClass).Observe(time.Since(start).Seconds())
+	p.metrics.RequestsTotal.WithLabelValues(backend, method, statusClass).Inc()
 }
 
-// getNextBackend uses an atomic counter for lock-free round-robin selection
-func (p *Proxy) getNextBackend() string {
-	// Fast modulo operation using bitwise AND when len is power of 2
-	next := atomic.AddUint32(&p.currentBackend, 1)
-	idx := int(next % uint32(len(p.config.BackendSockets)))
-	return p.config.BackendSockets[idx]
+// orderFrom returns the state's backends starting at the one picked by the
+// balancer and wrapping around, with ineligible backends moved to the end,
+// so retries still fall through every configured backend in a stable order.
+func (st *routeState) orderFrom(picked string) []*backendTarget {
+	start := 0
+	for i, b := range st.backends {
+		if b.address == picked {
+			start = i
+			break
+		}
+	}
+
+	rotated := make([]*backendTarget, 0, len(st.backends))
+	rotated = append(rotated, st.backends[start:]...)
+	rotated = append(rotated, st.backends[:start]...)
+
+	ordered := make([]*backendTarget, 0, len(rotated))
+	for _, b := range rotated {
+		if b.eligible() {
+			ordered = append(ordered, b)
+		}
+	}
+	for _, b := range rotated {
+		if !b.eligible() {
+			ordered = append(ordered, b)
+		}
+	}
+
+	return ordered
+}
+
+// routeFor resolves the route that should serve host, falling back to the
+// "*" route when no route matches and the fallback route exists.
+func (p *Proxy) routeFor(host string) *route {
+	if r, ok := p.routes[host]; ok {
+		return r
+	}
+	return p.fallback
+}
+
+// routeByHost resolves a route by its exact configured host key (including
+// the "*" fallback key), without falling through to the fallback route for
+// unrecognized hosts the way routeFor does. It's used by the admin API,
+// which operates on a specific route rather than a live request.
+func (p *Proxy) routeByHost(host string) *route {
+	if host == config.FallbackHost {
+		return p.fallback
+	}
+	return p.routes[host]
+}
+
+// allBackends returns every backend across every route, including the
+// fallback route, as of the time it's called.
+func (p *Proxy) allBackends() []*backendTarget {
+	var backends []*backendTarget
+	for _, r := range p.allRoutes() {
+		backends = append(backends, r.snapshot().backends...)
+	}
+	return backends
+}
+
+// listen opens a TCP listener on addr with SO_REUSEPORT set, so a freshly
+// started listener can bind the same address a still-draining one is
+// holding onto during a reload-triggered restart.
+func listen(addr string) (net.Listener, error) {
+	lc := net.ListenConfig{Control: controlReusePort}
+	return lc.Listen(context.Background(), "tcp", addr)
 }
 
 // Start begins accepting incoming connections and forwarding requests
 // to backend servers. It blocks until the server is shut down or encounters
 // an error.
 func (p *Proxy) Start() error {
-	if p.config.Logging.Enabled {
+	rc := p.requestConfig.Load()
+
+	ln, err := listen(rc.listenAddress)
+	if err != nil {
+		return err
+	}
+
+	p.serverMu.Lock()
+	p.listener = ln
+	server := p.server
+	p.serverMu.Unlock()
+
+	if rc.loggingEnabled {
 		p.logger.Info("starting proxy server",
-			"address", p.config.Server.ListenAddress,
-			"backends", p.config.BackendSockets,
+			"address", rc.listenAddress,
+			"routes", len(p.routes),
 		)
 	}
-	return p.server.ListenAndServe(p.config.Server.ListenAddress)
+
+	return server.Serve(ln)
+}
+
+// rebindListener opens a new listener and fasthttp.Server on addr and
+// starts serving on it, handing the previous listener's server off to drain
+// its in-flight requests in the background. Callers must hold adminMu.
+func (p *Proxy) rebindListener(addr string) error {
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+
+	newServer := &fasthttp.Server{Handler: p.handleRequest}
+
+	p.serverMu.Lock()
+	oldServer := p.server
+	p.server = newServer
+	p.listener = ln
+	p.serverMu.Unlock()
+
+	go func() {
+		if err := newServer.Serve(ln); err != nil {
+			p.logger.Error("rebound proxy listener stopped serving", "address", addr, "error", err)
+		}
+	}()
+
+	go func() {
+		if err := oldServer.Shutdown(); err != nil {
+			p.logger.Error("error draining previous proxy listener", "error", err)
+		}
+	}()
+
+	if p.requestConfig.Load().loggingEnabled {
+		p.logger.Info("proxy listener rebound", "address", addr)
+	}
+
+	return nil
 }
 
 // Shutdown gracefully stops the proxy server, allowing in-flight requests
 // to complete before shutting down. It returns any error encountered during
 // the shutdown process.
 func (p *Proxy) Shutdown() error {
-	if p.config.Logging.Enabled {
+	loggingEnabled := p.requestConfig.Load().loggingEnabled
+
+	if loggingEnabled {
 		p.logger.Info("shutting down proxy server")
 	}
 
-	err := p.server.Shutdown()
+	p.serverMu.Lock()
+	server := p.server
+	p.serverMu.Unlock()
 
-	for backend, client := range p.clients {
-		if p.config.Logging.Enabled {
-			p.logger.Debug("closing idle connections for backend", "backend", backend)
+	err := server.Shutdown()
+
+	if p.stopPool != nil {
+		close(p.stopPool)
+	}
+
+	if p.health != nil {
+		close(p.health.stop)
+	}
+
+	for _, backend := range p.allBackends() {
+		if loggingEnabled {
+			p.logger.Debug("closing idle connections for backend", "backend", backend.address)
 		}
-		client.CloseIdleConnections()
+		backend.client.CloseIdleConnections()
 	}
 
 	return err
 }
+
+// allRoutes returns every configured route, including the fallback route.
+func (p *Proxy) allRoutes() []*route {
+	routes := make([]*route, 0, len(p.routes)+1)
+	for _, r := range p.routes {
+		routes = append(routes, r)
+	}
+	if p.fallback != nil {
+		routes = append(routes, p.fallback)
+	}
+	return routes
+}
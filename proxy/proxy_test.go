@@ -0,0 +1,323 @@
+package proxy
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidalecrim/extreme/config"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// newFakeBackend starts handler behind an in-memory fasthttp server and
+// returns a backendTarget whose client dials straight into it, with no real
+// socket involved.
+func newFakeBackend(t *testing.T, address string, retries int, timeout time.Duration, handler fasthttp.RequestHandler) *backendTarget {
+	t.Helper()
+
+	ln := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: handler}
+
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+		_ = ln.Close()
+	})
+
+	client := &fasthttp.HostClient{
+		Addr: address,
+		Dial: func(string) (net.Conn, error) { return ln.Dial() },
+	}
+
+	return &backendTarget{
+		address: address,
+		client:  client,
+		retries: retries,
+		timeout: timeout,
+	}
+}
+
+func newTestRoute(host string, backends ...*backendTarget) *route {
+	byAddress := make(map[string]*backendTarget, len(backends))
+	addresses := make([]string, len(backends))
+	for i, b := range backends {
+		byAddress[b.address] = b
+		addresses[i] = b.address
+	}
+
+	st := &routeState{
+		backends:  backends,
+		byAddress: byAddress,
+		balancer:  newBalancer(routeBalancerConfig{Strategy: StrategyRoundRobin}, addresses, func(string) bool { return true }),
+	}
+
+	r := &route{host: host}
+	r.state.Store(st)
+	return r
+}
+
+func newTestProxy(routes map[string]*route, fallback *route) *Proxy {
+	cfg := &config.Config{
+		Server: config.ServerConfig{NotFoundBody: "not found"},
+	}
+
+	p := &Proxy{
+		config:   cfg,
+		routes:   routes,
+		fallback: fallback,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	p.requestConfig.Store(snapshotRequestConfig(cfg))
+	return p
+}
+
+func doRequest(p *Proxy, host string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.SetRequestURI("http://" + host + "/")
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+
+	p.handleRequest(&ctx)
+	return &ctx
+}
+
+func TestHandleRequestFallbackRoute(t *testing.T) {
+	backend := newFakeBackend(t, "fallback-backend", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+
+	fallback := newTestRoute(config.FallbackHost, backend)
+	p := newTestProxy(map[string]*route{}, fallback)
+
+	ctx := doRequest(p, "unmatched.example.com")
+
+	if got := ctx.Response.StatusCode(); got != fasthttp.StatusOK {
+		t.Fatalf("expected fallback route to serve unmatched host with 200, got %d", got)
+	}
+}
+
+func TestHandleRequestUnmatchedHostWithoutFallback(t *testing.T) {
+	p := newTestProxy(map[string]*route{}, nil)
+
+	ctx := doRequest(p, "unmatched.example.com")
+
+	if got := ctx.Response.StatusCode(); got != fasthttp.StatusNotFound {
+		t.Fatalf("expected 404 for unmatched host with no fallback route, got %d", got)
+	}
+	if got := string(ctx.Response.Body()); got != "not found" {
+		t.Fatalf("expected configured NotFoundBody, got %q", got)
+	}
+}
+
+// For a freshly built two-backend round-robin route, the very first Pick
+// always lands on the second backend passed to newTestRoute: roundRobinBalancer
+// pre-increments its counter, so the first call computes index 1%2. The
+// tests below rely on that determinism to control which backend's Retries
+// field ends up governing the attempt budget.
+
+func TestHandleRequestRetryExhaustion(t *testing.T) {
+	var firstCalls, secondCalls atomic.Int32
+
+	first := newFakeBackend(t, "backend-1", 1, 0, func(ctx *fasthttp.RequestCtx) {
+		firstCalls.Add(1)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	})
+	second := newFakeBackend(t, "backend-2", 1, 0, func(ctx *fasthttp.RequestCtx) {
+		secondCalls.Add(1)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	})
+
+	rt := newTestRoute(config.FallbackHost, first, second)
+	p := newTestProxy(map[string]*route{}, rt)
+
+	ctx := doRequest(p, "example.com")
+
+	if got := ctx.Response.StatusCode(); got != fasthttp.StatusBadGateway {
+		t.Fatalf("expected 502 once retries are exhausted, got %d", got)
+	}
+	// Both backends are configured with retries == 1, so the picked
+	// backend's budget covers exactly the other, different backend too,
+	// and neither is retried more than once.
+	if firstCalls.Load() != 1 {
+		t.Fatalf("expected first backend to be tried exactly once, got %d", firstCalls.Load())
+	}
+	if secondCalls.Load() != 1 {
+		t.Fatalf("expected second backend to be tried exactly once, got %d", secondCalls.Load())
+	}
+}
+
+func TestHandleRequestRetriesBoundAdditionalBackends(t *testing.T) {
+	var neverCalls, pickedCalls atomic.Int32
+
+	never := newFakeBackend(t, "backend-never", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		neverCalls.Add(1)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+	picked := newFakeBackend(t, "backend-picked", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		pickedCalls.Add(1)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	})
+
+	// picked.retries == 0, so the route must give up after it even though a
+	// healthy second backend is configured.
+	rt := newTestRoute(config.FallbackHost, never, picked)
+	p := newTestProxy(map[string]*route{}, rt)
+
+	ctx := doRequest(p, "example.com")
+
+	if got := ctx.Response.StatusCode(); got != fasthttp.StatusBadGateway {
+		t.Fatalf("expected 502 when the picked backend's retries is 0, got %d", got)
+	}
+	if pickedCalls.Load() != 1 {
+		t.Fatalf("expected the picked backend to be tried exactly once, got %d", pickedCalls.Load())
+	}
+	if neverCalls.Load() != 0 {
+		t.Fatalf("expected the other backend not to be tried, got %d calls", neverCalls.Load())
+	}
+}
+
+func TestHandleRequestPerBackendTimeoutOverride(t *testing.T) {
+	fast := newFakeBackend(t, "fast-backend", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+	slow := newFakeBackend(t, "slow-backend", 1, 10*time.Millisecond, func(ctx *fasthttp.RequestCtx) {
+		time.Sleep(50 * time.Millisecond)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+
+	// slow is picked first (see note above) and its 10ms timeout fires well
+	// before its 50ms handler responds, so the request must fall through to
+	// fast, which has no timeout override of its own.
+	rt := newTestRoute(config.FallbackHost, fast, slow)
+	p := newTestProxy(map[string]*route{}, rt)
+
+	ctx := doRequest(p, "example.com")
+
+	if got := ctx.Response.StatusCode(); got != fasthttp.StatusOK {
+		t.Fatalf("expected the request to fall through to the fast backend, got %d", got)
+	}
+}
+
+// TestHandleRequestRaceOnConcurrentReload exercises handleRequest and
+// Reload concurrently under the race detector: handleRequest must not read
+// p.config.Server's fields directly while a concurrent Reload replaces
+// p.config.Server wholesale.
+func TestHandleRequestRaceOnConcurrentReload(t *testing.T) {
+	backend := newFakeBackend(t, "fallback-backend", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+
+	fallback := newTestRoute(config.FallbackHost, backend)
+	p := newTestProxy(map[string]*route{}, fallback)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				doRequest(p, "example.com")
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			p.Reload(&config.Config{
+				Server: config.ServerConfig{NotFoundBody: "not found"},
+			})
+		}
+		close(stop)
+	}()
+
+	wg.Wait()
+}
+
+// TestRebindListenerDrainsInFlightRequestWhileServingNewConnections exercises
+// the mechanics a SIGHUP-triggered Reload relies on: rebindListener must let
+// a request already in flight on the old listener finish normally, while a
+// new request against the same address is picked up by the freshly bound
+// listener instead of failing with "connection refused".
+func TestRebindListenerDrainsInFlightRequestWhileServingNewConnections(t *testing.T) {
+	release := make(chan struct{})
+	var inFlightSeen atomic.Bool
+
+	backend := newFakeBackend(t, "slow-backend", 0, 0, func(ctx *fasthttp.RequestCtx) {
+		if !inFlightSeen.Swap(true) {
+			<-release
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+	fallback := newTestRoute(config.FallbackHost, backend)
+	p := newTestProxy(map[string]*route{}, fallback)
+
+	ln, err := listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	p.server = &fasthttp.Server{Handler: p.handleRequest}
+	p.listener = ln
+	go func() {
+		_ = p.server.Serve(ln)
+	}()
+
+	inFlightDone := make(chan error, 1)
+	go func() {
+		_, err := http.Get("http://" + addr + "/")
+		inFlightDone <- err
+	}()
+
+	for !inFlightSeen.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := p.rebindListener(addr); err != nil {
+		t.Fatalf("rebindListener: %v", err)
+	}
+
+	var reboundResp *http.Response
+	for i := 0; i < 100; i++ {
+		reboundResp, err = http.Get("http://" + addr + "/")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("request against the rebound listener: %v", err)
+	}
+	reboundResp.Body.Close()
+	if reboundResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from the rebound listener, got %d", reboundResp.StatusCode)
+	}
+
+	close(release)
+
+	select {
+	case err := <-inFlightDone:
+		if err != nil {
+			t.Fatalf("in-flight request across the rebind failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight request across the rebind never completed")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux && (amd64 || 386 || arm)
+
+package proxy
+
+import (
+	"syscall"
+)
+
+// soReusePort is SO_REUSEPORT's value in the Linux kernel headers
+// (include/uapi/asm-generic/socket.h) for amd64, 386, and arm, the only
+// Linux architectures for which the syscall package leaves SO_REUSEPORT
+// undefined (see reuseport_linux_other.go for the rest — note MIPS uses a
+// different value, 0x200, so it can't share this constant).
+const soReusePort = 0xf
+
+// controlReusePort sets SO_REUSEPORT on the listening socket before it's
+// bound, via net.ListenConfig.Control. This lets a freshly started listener
+// bind the same address as one still draining after a SIGHUP reload,
+// instead of failing with "address already in use".
+func controlReusePort(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd
+
+package proxy
+
+import "syscall"
+
+// controlReusePort is a no-op on platforms without SO_REUSEPORT: the
+// listener binds normally, so a SIGHUP reload that changes ListenAddress
+// still works, but rebinding the exact same address before the old
+// listener closes will fail with "address already in use".
+func controlReusePort(_, _ string, _ syscall.RawConn) error {
+	return nil
+}
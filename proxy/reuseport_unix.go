@@ -0,0 +1,24 @@
+//go:build darwin || freebsd
+
+package proxy
+
+import (
+	"syscall"
+)
+
+// controlReusePort sets SO_REUSEPORT on the listening socket before it's
+// bound, via net.ListenConfig.Control. This lets a freshly started listener
+// bind the same address as one still draining after a SIGHUP reload,
+// instead of failing with "address already in use".
+func controlReusePort(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}